@@ -0,0 +1,119 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/aws/amazon-cloudwatch-agent/cfg/agentinfo"
+	internalaws "github.com/aws/amazon-cloudwatch-agent/cfg/aws"
+	handlers "github.com/aws/amazon-cloudwatch-agent/handlers"
+	"github.com/aws/amazon-cloudwatch-agent/internal/retryer"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/influxdata/telegraf"
+)
+
+// clientCacheKey identifies the set of credentials/endpoint a CloudWatch client is built
+// from. Plugin instances that resolve to the same key share one SDK client, one retryer,
+// and one rate limiter instead of each opening their own.
+type clientCacheKey struct {
+	Region           string
+	RoleARN          string
+	Profile          string
+	EndpointOverride string
+	AccessKey        string
+}
+
+// cachedClient bundles everything Connect() needs that is safe, and desirable, to share
+// across CloudWatch output plugin instances pointed at the same credentials/endpoint.
+//
+// refs counts how many CloudWatch instances currently hold this client, so that Close() on
+// one instance doesn't stop the retryer (or close out the publish semaphore) while others
+// are still using it; it is only torn down once the last holder releases it.
+type cachedClient struct {
+	svc        cloudwatchiface.CloudWatchAPI
+	logsSvc    cloudwatchlogsiface.CloudWatchLogsAPI
+	retryer    *retryer.LogThrottleRetryer
+	limiter    *rate.Limiter
+	publishSem chan struct{}
+	refs       int
+}
+
+var (
+	clientCacheMu sync.Mutex
+	clientCache   = make(map[clientCacheKey]*cachedClient)
+)
+
+// getOrCreateClient returns the cached client for key, building it (and its shared rate
+// limiter) the first time that key is seen. rateLimitPerSecond only takes effect when the
+// entry is created; later callers inherit the limiter set up by whichever plugin instance
+// connected first. Every successful call must be paired with a releaseClient(key) from
+// Close() so the client is only torn down once nothing references it anymore.
+func getOrCreateClient(key clientCacheKey, credentialConfig *internalaws.CredentialConfig, endpointOverride string, log telegraf.Logger, rateLimitPerSecond int) *cachedClient {
+	clientCacheMu.Lock()
+	defer clientCacheMu.Unlock()
+
+	if client, ok := clientCache[key]; ok {
+		client.refs++
+		return client
+	}
+
+	configProvider := credentialConfig.Credentials()
+
+	logThrottleRetryer := retryer.NewLogThrottleRetryer(log)
+	svc := cloudwatch.New(
+		configProvider,
+		&aws.Config{
+			Endpoint: aws.String(endpointOverride),
+			Retryer:  logThrottleRetryer,
+		})
+
+	svc.Handlers.Build.PushBackNamed(handlers.NewRequestCompressionHandler([]string{opPutLogEvents, opPutMetricData}))
+	svc.Handlers.Build.PushBackNamed(handlers.NewCustomHeaderHandler("User-Agent", agentinfo.UserAgent()))
+
+	logsSvc := cloudwatchlogs.New(
+		configProvider,
+		&aws.Config{
+			Endpoint: aws.String(endpointOverride),
+			Retryer:  logThrottleRetryer,
+		})
+	logsSvc.Handlers.Build.PushBackNamed(handlers.NewRequestCompressionHandler([]string{opPutLogEvents, opPutMetricData}))
+	logsSvc.Handlers.Build.PushBackNamed(handlers.NewCustomHeaderHandler("User-Agent", agentinfo.UserAgent()))
+
+	client := &cachedClient{
+		svc:        svc,
+		logsSvc:    logsSvc,
+		retryer:    logThrottleRetryer,
+		limiter:    rate.NewLimiter(rate.Limit(rateLimitPerSecond), rateLimitPerSecond),
+		publishSem: make(chan struct{}, maxConcurrentPublisher),
+		refs:       1,
+	}
+	clientCache[key] = client
+	return client
+}
+
+// releaseClient drops one reference to the cached client at key, stopping its retryer and
+// evicting it from the cache once nothing references it anymore. It is a no-op if key was
+// never (or is no longer) cached.
+func releaseClient(key clientCacheKey) {
+	clientCacheMu.Lock()
+	defer clientCacheMu.Unlock()
+
+	client, ok := clientCache[key]
+	if !ok {
+		return
+	}
+	client.refs--
+	if client.refs > 0 {
+		return
+	}
+	client.retryer.Stop()
+	delete(clientCache, key)
+}
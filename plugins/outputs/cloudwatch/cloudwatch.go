@@ -4,6 +4,8 @@
 package cloudwatch
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"math"
 	"reflect"
@@ -13,18 +15,19 @@ import (
 	"sync"
 	"time"
 
+	"golang.org/x/time/rate"
+
 	"github.com/aws/amazon-cloudwatch-agent/internal/publisher"
 	"github.com/aws/amazon-cloudwatch-agent/internal/retryer"
 
-	"github.com/aws/amazon-cloudwatch-agent/cfg/agentinfo"
 	internalaws "github.com/aws/amazon-cloudwatch-agent/cfg/aws"
-	handlers "github.com/aws/amazon-cloudwatch-agent/handlers"
 	"github.com/aws/amazon-cloudwatch-agent/internal"
 	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
@@ -40,6 +43,12 @@ const (
 	highResolutionTagKey           = "aws:StorageResolution"
 	defaultRetryCount              = 5 // this is the retry count, the total attempts would be retry count + 1 at most.
 	backoffRetryBase               = 200
+	defaultRateLimitPerSecond      = 40 // stay under the 50 TPS PutMetricData account limit
+)
+
+// statisticType values accepted for the `statistic_type` config option / per-metric override.
+const (
+	statisticTypeMinMaxSumCount = "min_max_sum_count"
 )
 
 const (
@@ -60,8 +69,39 @@ type CloudWatch struct {
 	MaxDatumsPerCall   int                      `toml:"max_datums_per_call"`
 	MaxValuesPerDatum  int                      `toml:"max_values_per_datum"`
 	MetricConfigs      []MetricDecorationConfig `toml:"metric_decoration"`
+	MetricFilters      []MetricFilterConfig     `toml:"metric_filter"`
 	RollupDimensions   [][]string               `toml:"rollup_dimensions"`
-	Namespace          string                   `toml:"namespace"` // CloudWatch Metrics Namespace
+	Namespace          string                   `toml:"namespace"`  // CloudWatch Metrics Namespace
+	Namespaces         []string                 `toml:"namespaces"` // Additional namespaces metrics can be routed to via metric_decoration
+
+	// WriteStatisticsOnly, when true, transmits distributions using only StatisticValues
+	// (Min/Max/Sum/SampleCount), omitting the Values/Counts arrays. A metric_decoration
+	// "statistic_only" = true enables this for that metric alone; it cannot disable it for a
+	// metric while this is true.
+	WriteStatisticsOnly bool `toml:"write_statistics_only"`
+	// StatisticType, when set to "min_max_sum_count", splits a plain scalar field into
+	// four synthetic datums (suffixed _min/_max/_sum/_count) instead of a single raw value.
+	// Can be overridden per metric via the metric_decoration "statistic_type" field.
+	StatisticType string `toml:"statistic_type"`
+
+	// RateLimitPerSecond caps the rate, shared cooperatively with every other CloudWatch
+	// output plugin instance that resolves to the same underlying client, at which
+	// WriteToCloudWatch is allowed to call PutMetricData. Defaults to 40 to stay under the
+	// 50 TPS PutMetricData account limit.
+	RateLimitPerSecond int `toml:"ratelimit_per_second"`
+
+	// EmitMode selects how metrics leave the agent. "" (default) uses PutMetricData; "emf"
+	// ships CloudWatch Embedded Metric Format documents via PutLogEvents instead.
+	EmitMode      string `toml:"emit_mode"`
+	LogGroupName  string `toml:"log_group_name"`
+	LogStreamName string `toml:"log_stream_name"`
+
+	// SpoolDirectory, when set, enables an on-disk write-ahead log of buffered metric
+	// batches under this directory so a crash or sustained CloudWatch outage does not drop
+	// them. SpoolMaxBytes caps its total size (oldest segments are evicted first). Not
+	// supported together with EmitMode "emf"; Connect returns an error if both are set.
+	SpoolDirectory string `toml:"spool_directory"`
+	SpoolMaxBytes  int64  `toml:"spool_max_bytes"`
 
 	Log telegraf.Logger `toml:"-"`
 
@@ -70,15 +110,23 @@ type CloudWatch struct {
 	aggregatorShutdownChan chan struct{}
 	aggregatorWaitGroup    sync.WaitGroup
 	metricChan             chan telegraf.Metric
-	datumBatchChan         chan []*cloudwatch.MetricDatum
+	datumBatchChan         chan interface{} // carries cloudWatchPayload, or logEventPayload when EmitMode is "emf"
 	datumBatchFullChan     chan bool
-	metricDatumBatch       *MetricDatumBatch
+	metricDatumBatches     map[string]*MetricDatumBatch
 	shutdownChan           chan struct{}
 	pushTicker             *time.Ticker
 	metricDecorations      *MetricDecorations
 	retries                int
 	publisher              *publisher.Publisher
 	retryer                *retryer.LogThrottleRetryer
+	limiter                *rate.Limiter
+	logsSvc                cloudwatchlogsiface.CloudWatchLogsAPI
+	logEventBatches        map[string]*logEventBatch
+	spool                  *spool
+	compiledFilters        []compiledMetricFilter
+	namespaceAllowed       map[string]bool
+	publishSem             chan struct{}
+	clientCacheKey         clientCacheKey
 }
 
 var sampleConfig = `
@@ -103,8 +151,49 @@ var sampleConfig = `
   ## Namespace for the CloudWatch MetricDatums
   namespace = "InfluxData/Telegraf"
 
+  ## Additional namespaces that metrics can be routed to via the metric_decoration
+  ## "namespace" field. A metric without an override is still sent to "namespace" above. A
+  ## metric_decoration "namespace" override that names anything other than "namespace" or one
+  ## of these is rejected and falls back to "namespace" above.
+  # namespaces = ["CWAgent/System", "Custom/App"]
+
   ## RollupDimensions
   # RollupDimensions = [["host"],["host", "ImageId"],[]]
+
+  ## Emit mode: "" (default) sends PutMetricData requests; "emf" instead ships CloudWatch
+  ## Embedded Metric Format log events via PutLogEvents to log_group_name/log_stream_name.
+  ## log_group_name/log_stream_name are created automatically (if missing) on first use.
+  # emit_mode = ""
+  # log_group_name = ""
+  # log_stream_name = ""
+
+  ## Write-ahead log for buffered metric batches, so a crash or sustained CloudWatch outage
+  ## doesn't drop them. Disabled unless spool_directory is set. Not supported with
+  ## emit_mode = "emf".
+  # spool_directory = ""
+  # spool_max_bytes = 104857600
+
+  ## Drop, keep, or rewrite metrics/dimensions before they reach the aggregator, modeled on
+  ## Prometheus relabel_config. action is one of "drop", "keep", "replace", "labeldrop",
+  ## "labelkeep". source_labels are joined with ";" and matched against regex.
+  # [[outputs.cloudwatch.metric_filter]]
+  #   action = "drop"
+  #   source_labels = ["host"]
+  #   regex = "^test-.*$"
+
+  ## Send distributions using only StatisticValues (Min/Max/Sum/SampleCount), omitting
+  ## the Values/Counts arrays. A metric_decoration "statistic_only" = true enables it for
+  ## that metric alone.
+  # write_statistics_only = false
+
+  ## Rate limit, in PutMetricData calls per second, shared cooperatively with every other
+  ## CloudWatch output plugin instance resolving to the same underlying client (same
+  ## region/role_arn/profile/endpoint_override/access_key).
+  # ratelimit_per_second = 40
+
+  ## Split a scalar field into synthetic min/max/sum/count datums instead of sending the
+  ## raw value. Overridable per metric via metric_decoration. Valid value: "min_max_sum_count"
+  # statistic_type = ""
 `
 
 func (c *CloudWatch) SampleConfig() string {
@@ -118,12 +207,52 @@ func (c *CloudWatch) Description() string {
 func (c *CloudWatch) Connect() error {
 	var err error
 
-	c.publisher, _ = publisher.NewPublisher(publisher.NewNonBlockingFifoQueue(metricChanBufferSize), maxConcurrentPublisher, 2*time.Second, c.WriteToCloudWatch)
+	publishFunc := c.WriteToCloudWatch
+	if c.EmitMode == emitModeEMF {
+		publishFunc = c.WriteLogEventsToCloudWatch
+	}
+	c.publisher, _ = publisher.NewPublisher(publisher.NewNonBlockingFifoQueue(metricChanBufferSize), maxConcurrentPublisher, 2*time.Second, publishFunc)
 
 	if c.metricDecorations, err = NewMetricDecorations(c.MetricConfigs); err != nil {
 		return err
 	}
 
+	if c.compiledFilters, err = compileMetricFilters(c.MetricFilters); err != nil {
+		return err
+	}
+
+	// namespaceAllowed bounds what a metric_decoration "namespace" override can route a
+	// metric to: the plugin's own Namespace, plus whatever it declared via Namespaces.
+	c.namespaceAllowed = make(map[string]bool, len(c.Namespaces)+1)
+	c.namespaceAllowed[c.Namespace] = true
+	for _, namespace := range c.Namespaces {
+		c.namespaceAllowed[namespace] = true
+	}
+
+	if c.RateLimitPerSecond == 0 {
+		c.RateLimitPerSecond = defaultRateLimitPerSecond
+	}
+
+	if c.SpoolDirectory != "" && c.EmitMode == emitModeEMF {
+		// The spool only ever durably stores/replays cloudWatchPayload (PutMetricData)
+		// records; replaying one into WriteLogEventsToCloudWatch would panic on the type
+		// assertion. Reject the combination outright instead of losing events silently.
+		return fmt.Errorf("cloudwatch: spool_directory is not supported with emit_mode = %q", emitModeEMF)
+	}
+
+	// Validate and open the spool before acquiring the shared client below, so a failure
+	// here returns without having to release a client ref it never needed.
+	if c.SpoolDirectory != "" {
+		if c.SpoolMaxBytes == 0 {
+			c.SpoolMaxBytes = defaultSpoolMaxBytes
+		}
+		s, err := openSpool(c.SpoolDirectory, c.SpoolMaxBytes)
+		if err != nil {
+			return err
+		}
+		c.spool = s
+	}
+
 	credentialConfig := &internalaws.CredentialConfig{
 		Region:    c.Region,
 		AccessKey: c.AccessKey,
@@ -133,31 +262,38 @@ func (c *CloudWatch) Connect() error {
 		Filename:  c.Filename,
 		Token:     c.Token,
 	}
-	configProvider := credentialConfig.Credentials()
-
-	logThrottleRetryer := retryer.NewLogThrottleRetryer(c.Log)
-	svc := cloudwatch.New(
-		configProvider,
-		&aws.Config{
-			Endpoint:   aws.String(c.EndpointOverride),
-			Retryer:    logThrottleRetryer,
-		})
 
-	svc.Handlers.Build.PushBackNamed(handlers.NewRequestCompressionHandler([]string{opPutLogEvents, opPutMetricData}))
-	svc.Handlers.Build.PushBackNamed(handlers.NewCustomHeaderHandler("User-Agent", agentinfo.UserAgent()))
+	c.clientCacheKey = clientCacheKey{
+		Region:           c.Region,
+		RoleARN:          c.RoleARN,
+		Profile:          c.Profile,
+		EndpointOverride: c.EndpointOverride,
+		AccessKey:        c.AccessKey,
+	}
+	cachedClient := getOrCreateClient(c.clientCacheKey, credentialConfig, c.EndpointOverride, c.Log, c.RateLimitPerSecond)
 
 	//Format unique roll up list
 	c.RollupDimensions = GetUniqueRollupList(c.RollupDimensions)
 
-	c.svc = svc
-	c.retryer = logThrottleRetryer
+	c.svc = cachedClient.svc
+	c.retryer = cachedClient.retryer
+	c.limiter = cachedClient.limiter
+	// publishSem caps PutMetricData/PutLogEvents concurrency across every CloudWatch output
+	// instance sharing this client, not just this one, so maxConcurrentPublisher is a
+	// ceiling for all of those instances together rather than a per-instance one.
+	c.publishSem = cachedClient.publishSem
+
+	if c.EmitMode == emitModeEMF {
+		c.logsSvc = cachedClient.logsSvc
+	}
+
 	c.startRoutines()
 	return nil
 }
 
 func (c *CloudWatch) startRoutines() {
 	c.metricChan = make(chan telegraf.Metric, metricChanBufferSize)
-	c.datumBatchChan = make(chan []*cloudwatch.MetricDatum, datumBatchChanBufferSize)
+	c.datumBatchChan = make(chan interface{}, datumBatchChanBufferSize)
 	c.datumBatchFullChan = make(chan bool, 1)
 	c.shutdownChan = make(chan struct{})
 	c.aggregatorShutdownChan = make(chan struct{})
@@ -172,8 +308,13 @@ func (c *CloudWatch) startRoutines() {
 		c.MaxValuesPerDatum = defaultMaxValuesPerDatum
 	}
 	setNewDistributionFunc(c.MaxValuesPerDatum)
-	perRequestConstSize := overallConstPerRequestSize + len(c.Namespace) + namespaceOverheads
-	c.metricDatumBatch = newMetricDatumBatch(c.MaxDatumsPerCall, perRequestConstSize)
+	c.metricDatumBatches = make(map[string]*MetricDatumBatch)
+	if c.EmitMode == emitModeEMF {
+		c.logEventBatches = make(map[string]*logEventBatch)
+	}
+	if c.spool != nil {
+		c.replaySpool()
+	}
 	go c.pushMetricDatum()
 	go c.publish()
 }
@@ -195,13 +336,21 @@ func (c *CloudWatch) Close() error {
 	}
 	close(c.shutdownChan)
 	c.publisher.Close()
-	c.retryer.Stop()
+	// releaseClient only actually stops the retryer once every CloudWatch instance sharing
+	// this client has released it, since other instances may still be relying on it.
+	releaseClient(c.clientCacheKey)
+	if c.spool != nil && c.spool.active != nil && c.spool.active.file != nil {
+		c.spool.active.file.Close()
+	}
 	log.Println("D! Stopped the CloudWatch output plugin")
 	return nil
 }
 
 func (c *CloudWatch) Write(metrics []telegraf.Metric) error {
 	for _, m := range metrics {
+		if m = c.filterAndRelabel(m); m == nil {
+			continue
+		}
 		c.aggregator.AddMetric(m)
 	}
 	return nil
@@ -216,22 +365,34 @@ func (c *CloudWatch) pushMetricDatum() {
 	for {
 		select {
 		case point := <-c.metricChan:
-			datums := c.BuildMetricDatum(point)
-			numberOfPartitions := len(datums)
-			for i := 0; i < numberOfPartitions; i++ {
-				c.metricDatumBatch.Partition = append(c.metricDatumBatch.Partition, datums[i])
-				c.metricDatumBatch.Size += payload(datums[i])
-				if c.metricDatumBatch.isFull() {
-					// if batch is full
-					c.datumBatchChan <- c.metricDatumBatch.Partition
-					c.metricDatumBatch.clear()
+			if c.EmitMode == emitModeEMF {
+				c.pushEMFLogEvents(point)
+				continue
+			}
+			datumsByNamespace := c.BuildMetricDatum(point)
+			for namespace, datums := range datumsByNamespace {
+				batch := c.getOrCreateBatch(namespace)
+				for _, datum := range datums {
+					batch.Partition = append(batch.Partition, datum)
+					batch.Size += payload(datum)
+					if batch.isFull() {
+						// if batch is full
+						c.enqueueBatch(namespace, batch.Partition)
+						batch.clear()
+					}
 				}
 			}
 		case <-ticker.C:
-			if c.timeToPublish(c.metricDatumBatch) {
-				// if the time to publish comes
-				c.datumBatchChan <- c.metricDatumBatch.Partition
-				c.metricDatumBatch.clear()
+			if c.EmitMode == emitModeEMF {
+				c.flushEMFLogEventBatches()
+				continue
+			}
+			for namespace, batch := range c.metricDatumBatches {
+				if c.timeToPublish(batch) {
+					// if the time to publish comes
+					c.enqueueBatch(namespace, batch.Partition)
+					batch.clear()
+				}
 			}
 		case <-c.shutdownChan:
 			return
@@ -239,6 +400,32 @@ func (c *CloudWatch) pushMetricDatum() {
 	}
 }
 
+// cloudWatchPayload is what gets handed off from the batching goroutine to the publisher;
+// each namespace is flushed and published independently of the others.
+type cloudWatchPayload struct {
+	Namespace string
+	Datums    []*cloudwatch.MetricDatum
+	// walRef is non-nil when this payload was first durably appended to the spool; it is
+	// acked (and its backing segment eventually deleted) once PutMetricData succeeds.
+	walRef *walRef
+}
+
+// enqueueBatch hands a batch (full, or due by ForceFlushInterval) off to the publisher, first appending it to the spool
+// write-ahead log (when one is configured) so the batch survives an agent crash or a
+// sustained CloudWatch outage until it is actually published successfully.
+func (c *CloudWatch) enqueueBatch(namespace string, datums []*cloudwatch.MetricDatum) {
+	payloadToSend := cloudWatchPayload{Namespace: namespace, Datums: datums}
+	if c.spool != nil {
+		ref, err := c.spool.Append(walRecord{Namespace: namespace, Datums: datums})
+		if err != nil {
+			log.Printf("E! cloudwatch: failed to append metric batch to spool, publishing without WAL backing: %v", err)
+		} else {
+			payloadToSend.walRef = ref
+		}
+	}
+	c.datumBatchChan <- payloadToSend
+}
+
 type MetricDatumBatch struct {
 	MaxDatumsPerCall    int
 	Partition           []*cloudwatch.MetricDatum
@@ -247,6 +434,18 @@ type MetricDatumBatch struct {
 	perRequestConstSize int
 }
 
+// getOrCreateBatch returns the in-flight batch for namespace, creating one (with its own
+// payload-size accounting) the first time that namespace is seen.
+func (c *CloudWatch) getOrCreateBatch(namespace string) *MetricDatumBatch {
+	if batch, ok := c.metricDatumBatches[namespace]; ok {
+		return batch
+	}
+	perRequestConstSize := overallConstPerRequestSize + len(namespace) + namespaceOverheads
+	batch := newMetricDatumBatch(c.MaxDatumsPerCall, perRequestConstSize)
+	c.metricDatumBatches[namespace] = batch
+	return batch
+}
+
 func newMetricDatumBatch(maxDatumsPerCall, perRequestConstSize int) *MetricDatumBatch {
 	return &MetricDatumBatch{
 		MaxDatumsPerCall:    maxDatumsPerCall,
@@ -337,11 +536,22 @@ func (c *CloudWatch) backoffSleep() {
 }
 
 func (c *CloudWatch) WriteToCloudWatch(req interface{}) {
-	datums := req.([]*cloudwatch.MetricDatum)
+	cwPayload := req.(cloudWatchPayload)
 	params := &cloudwatch.PutMetricDataInput{
-		MetricData: datums,
-		Namespace:  aws.String(c.Namespace),
+		MetricData: cwPayload.Datums,
+		Namespace:  aws.String(cwPayload.Namespace),
+	}
+	if c.limiter != nil {
+		// Block on the shared, cooperative rate limit before issuing the call, so that
+		// every CloudWatch output instance sharing this client stays under the account's
+		// PutMetricData TPS limit together rather than each having its own ceiling.
+		_ = c.limiter.Wait(context.Background())
+	}
+	if c.publishSem != nil {
+		c.publishSem <- struct{}{}
+		defer func() { <-c.publishSem }()
 	}
+
 	var err error
 	for i := 0; i < defaultRetryCount; i++ {
 		_, err = c.svc.PutMetricData(params)
@@ -372,6 +582,10 @@ func (c *CloudWatch) WriteToCloudWatch(req interface{}) {
 	}
 	if err != nil {
 		log.Println("E! WriteToCloudWatch failure, err: ", err)
+		return
+	}
+	if c.spool != nil && cwPayload.walRef != nil {
+		c.spool.Ack(cwPayload.walRef)
 	}
 }
 
@@ -400,9 +614,73 @@ func (c *CloudWatch) decorateMetricUnit(category string, name string) (decorated
 	return
 }
 
+// resolveNamespace returns the namespace a given field should be published under: the
+// metric_decoration "namespace" override if one is configured for this category/name and it
+// is one of Namespace/Namespaces, or the plugin-wide Namespace otherwise. An override outside
+// that declared set is rejected (and logged) rather than silently routing metrics to an
+// undeclared namespace.
+func (c *CloudWatch) resolveNamespace(category string, name string) string {
+	if c.metricDecorations != nil {
+		if namespace := c.metricDecorations.getNamespace(category, name); namespace != "" {
+			if c.namespaceAllowed[namespace] {
+				return namespace
+			}
+			log.Printf("W! cloudwatch: metric_decoration namespace override %q for %s.%s is not in namespace/namespaces, falling back to %q", namespace, category, name, c.Namespace)
+		}
+	}
+	return c.Namespace
+}
+
+// decorateStatisticOnly resolves whether a distribution should be transmitted using only
+// StatisticValues, falling back to the plugin-wide WriteStatisticsOnly setting.
+func (c *CloudWatch) decorateStatisticOnly(category string, name string) bool {
+	if c.metricDecorations != nil && c.metricDecorations.getStatisticOnly(category, name) {
+		return true
+	}
+	return c.WriteStatisticsOnly
+}
+
+// decorateStatisticType resolves the per-metric statistic_type override, falling back to
+// the plugin-wide StatisticType setting.
+func (c *CloudWatch) decorateStatisticType(category string, name string) string {
+	if c.metricDecorations != nil {
+		if statisticType := c.metricDecorations.getStatisticType(category, name); statisticType != "" {
+			return statisticType
+		}
+	}
+	return c.StatisticType
+}
+
+// buildScalarStatisticDatums splits a single scalar value into four synthetic datums
+// (min/max/sum/count), following the same shape a pre-aggregated StatisticValues datum
+// would have, so downstream percentile queries work without keeping a full distribution.
+func buildScalarStatisticDatums(metricName string, unit string, dimensions []*cloudwatch.Dimension, timestamp time.Time, value float64, isHighResolution bool) []*cloudwatch.MetricDatum {
+	suffixes := [...]string{"min", "max", "sum", "count"}
+	values := [...]float64{value, value, value, 1}
+	datums := make([]*cloudwatch.MetricDatum, 0, len(suffixes))
+	for i, suffix := range suffixes {
+		datum := &cloudwatch.MetricDatum{
+			MetricName: aws.String(metricName + "_" + suffix),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(timestamp),
+			Value:      aws.Float64(values[i]),
+		}
+		if unit != "" {
+			datum.SetUnit(unit)
+		}
+		if isHighResolution {
+			datum.SetStorageResolution(1)
+		}
+		datums = append(datums, datum)
+	}
+	return datums
+}
+
 // Create MetricDatums according to metric roll up requirement for each field in a Point. Only fields with values that can be
-// converted to float64 are supported. Non-supported fields are skipped.
-func (c *CloudWatch) BuildMetricDatum(point telegraf.Metric) []*cloudwatch.MetricDatum {
+// converted to float64 are supported. Non-supported fields are skipped. The result is grouped
+// by the namespace each field resolves to (the plugin-wide Namespace, unless a metric_decoration
+// "namespace" override applies to that field).
+func (c *CloudWatch) BuildMetricDatum(point telegraf.Metric) map[string][]*cloudwatch.MetricDatum {
 	//high resolution logic
 	isHighResolution := false
 	highResolutionValue, ok := point.Tags()[highResolutionTagKey]
@@ -415,11 +693,12 @@ func (c *CloudWatch) BuildMetricDatum(point telegraf.Metric) []*cloudwatch.Metri
 	dimensionsList := c.ProcessRollup(rawDimensions)
 	//https://pratheekadidela.in/2016/02/11/is-append-in-go-efficient/
 	//https://www.ardanlabs.com/blog/2013/08/understanding-slices-in-go-programming.html
-	var datums []*cloudwatch.MetricDatum
+	datumsByNamespace := make(map[string][]*cloudwatch.MetricDatum)
 	for k, v := range point.Fields() {
 		var unit string
 		var value float64
 		var distList []distribution.Distribution
+		var fullDist distribution.Distribution
 
 		switch t := v.(type) {
 		case uint:
@@ -461,6 +740,7 @@ func (c *CloudWatch) BuildMetricDatum(point telegraf.Metric) []*cloudwatch.Metri
 			}
 			distList = resize(t, c.MaxValuesPerDatum)
 			unit = t.Unit()
+			fullDist = t
 		default:
 			// Skip unsupported type.
 			continue
@@ -470,9 +750,19 @@ func (c *CloudWatch) BuildMetricDatum(point telegraf.Metric) []*cloudwatch.Metri
 		if unit == "" {
 			unit = c.decorateMetricUnit(point.Name(), k)
 		}
+		statisticOnly := len(distList) > 0 && c.decorateStatisticOnly(point.Name(), k)
+		statisticType := ""
+		if len(distList) == 0 {
+			statisticType = c.decorateStatisticType(point.Name(), k)
+		}
+		namespace := c.resolveNamespace(point.Name(), k)
+		datums := datumsByNamespace[namespace]
 
 		for _, dimensions := range dimensionsList {
-			if len(distList) == 0 {
+			switch {
+			case len(distList) == 0 && statisticType == statisticTypeMinMaxSumCount:
+				datums = append(datums, buildScalarStatisticDatums(*metricName, unit, dimensions, point.Time(), value, isHighResolution)...)
+			case len(distList) == 0:
 				datum := &cloudwatch.MetricDatum{
 					MetricName: metricName,
 					Dimensions: dimensions,
@@ -486,7 +776,29 @@ func (c *CloudWatch) BuildMetricDatum(point telegraf.Metric) []*cloudwatch.Metri
 					datum.SetStorageResolution(1)
 				}
 				datums = append(datums, datum)
-			} else {
+			case statisticOnly:
+				// Skip the Values/Counts arrays entirely and rely solely on the
+				// pre-aggregated StatisticValues, keeping high-cardinality histogram
+				// payloads well under the PutMetricData request size limit.
+				datum := &cloudwatch.MetricDatum{
+					MetricName: metricName,
+					Dimensions: dimensions,
+					Timestamp:  aws.Time(point.Time()),
+				}
+				datum.SetStatisticValues(&cloudwatch.StatisticSet{
+					Maximum:     aws.Float64(fullDist.Maximum()),
+					Minimum:     aws.Float64(fullDist.Minimum()),
+					SampleCount: aws.Float64(fullDist.SampleCount()),
+					Sum:         aws.Float64(fullDist.Sum()),
+				})
+				if unit != "" {
+					datum.SetUnit(unit)
+				}
+				if isHighResolution {
+					datum.SetStorageResolution(1)
+				}
+				datums = append(datums, datum)
+			default:
 				for _, dist := range distList {
 					datum := &cloudwatch.MetricDatum{
 						MetricName: metricName,
@@ -512,8 +824,9 @@ func (c *CloudWatch) BuildMetricDatum(point telegraf.Metric) []*cloudwatch.Metri
 				}
 			}
 		}
+		datumsByNamespace[namespace] = datums
 	}
-	return datums
+	return datumsByNamespace
 }
 
 // Make a list of Dimensions by using a Point's tags. CloudWatch supports up to
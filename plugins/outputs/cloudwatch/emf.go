@@ -0,0 +1,356 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/influxdata/telegraf"
+)
+
+// emitModeEMF selects the EMF (Embedded Metric Format) output path over PutMetricData.
+const emitModeEMF = "emf"
+
+const (
+	// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_PutLogEvents.html
+	maxLogEventsPerCall    = 10000
+	maxLogEventBatchBytes  = 1048576 // 1MB
+	logEventOverheadBytes  = 26      // per-event overhead CloudWatch Logs adds on top of the message size
+	defaultHighResStorage  = 1
+	defaultStandardStorage = 60
+)
+
+// emfMetricDirective describes a single metric entry inside the _aws.CloudWatchMetrics
+// array of an EMF document.
+type emfMetricDirective struct {
+	Name              string `json:"Name"`
+	Unit              string `json:"Unit,omitempty"`
+	StorageResolution int    `json:"StorageResolution,omitempty"`
+}
+
+type emfMetricsDefinition struct {
+	Namespace  string               `json:"Namespace"`
+	Dimensions [][]string           `json:"Dimensions"`
+	Metrics    []emfMetricDirective `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64                  `json:"Timestamp"`
+	CloudWatchMetrics []emfMetricsDefinition `json:"CloudWatchMetrics"`
+}
+
+// BuildEMFLogs produces one EMF JSON document per field/dimension-set combination for
+// point, grouped by the namespace each field resolves to, mirroring the grouping
+// BuildMetricDatum uses for the PutMetricData path. Distributions are serialized using the
+// EMF Values/Counts arrays, chunked by MaxValuesPerDatum just like PutMetricData datums are.
+func (c *CloudWatch) BuildEMFLogs(point telegraf.Metric) map[string][][]byte {
+	isHighResolution := false
+	if highResolutionValue, ok := point.Tags()[highResolutionTagKey]; ok && strings.EqualFold(highResolutionValue, "true") {
+		isHighResolution = true
+		point.RemoveTag(highResolutionTagKey)
+	}
+	storageResolution := defaultStandardStorage
+	if isHighResolution {
+		storageResolution = defaultHighResStorage
+	}
+
+	rawDimensions := BuildDimensions(point.Tags())
+	dimensionsList := c.ProcessRollup(rawDimensions)
+
+	docsByNamespace := make(map[string][][]byte)
+	for k, v := range point.Fields() {
+		var unit string
+		var value float64
+		var distList []distribution.Distribution
+
+		switch t := v.(type) {
+		case uint:
+			value = float64(t)
+		case uint8:
+			value = float64(t)
+		case uint16:
+			value = float64(t)
+		case uint32:
+			value = float64(t)
+		case uint64:
+			value = float64(t)
+		case int:
+			value = float64(t)
+		case int8:
+			value = float64(t)
+		case int16:
+			value = float64(t)
+		case int32:
+			value = float64(t)
+		case int64:
+			value = float64(t)
+		case float32:
+			value = float64(t)
+		case float64:
+			value = t
+		case bool:
+			if t {
+				value = 1
+			} else {
+				value = 0
+			}
+		case time.Time:
+			value = float64(t.Unix())
+		case distribution.Distribution:
+			if t.Size() == 0 {
+				continue
+			}
+			distList = resize(t, c.MaxValuesPerDatum)
+			unit = t.Unit()
+		default:
+			continue
+		}
+
+		metricName := c.decorateMetricName(point.Name(), k)
+		if unit == "" {
+			unit = c.decorateMetricUnit(point.Name(), k)
+		}
+		namespace := c.resolveNamespace(point.Name(), k)
+
+		for _, dimensions := range dimensionsList {
+			dimensionNames := make([]string, 0, len(dimensions))
+			properties := make(map[string]interface{}, len(dimensions)+1)
+			for _, d := range dimensions {
+				dimensionNames = append(dimensionNames, *d.Name)
+				properties[*d.Name] = *d.Value
+			}
+
+			if len(distList) == 0 {
+				properties[metricName] = value
+				doc, err := buildEMFDocument(namespace, dimensionNames, properties, emfMetricDirective{Name: metricName, Unit: unit, StorageResolution: storageResolution}, point.Time())
+				if err != nil {
+					log.Printf("E! cloudwatch: failed to marshal EMF document for metric %s: %v", metricName, err)
+					continue
+				}
+				docsByNamespace[namespace] = append(docsByNamespace[namespace], doc)
+				continue
+			}
+
+			for _, dist := range distList {
+				values, counts := dist.ValuesAndCounts()
+				chunkProperties := make(map[string]interface{}, len(properties)+1)
+				for prop, propVal := range properties {
+					chunkProperties[prop] = propVal
+				}
+				chunkProperties[metricName] = map[string]interface{}{
+					"Values": values,
+					"Counts": counts,
+				}
+				doc, err := buildEMFDocument(namespace, dimensionNames, chunkProperties, emfMetricDirective{Name: metricName, Unit: unit, StorageResolution: storageResolution}, point.Time())
+				if err != nil {
+					log.Printf("E! cloudwatch: failed to marshal EMF document for metric %s: %v", metricName, err)
+					continue
+				}
+				docsByNamespace[namespace] = append(docsByNamespace[namespace], doc)
+			}
+		}
+	}
+	return docsByNamespace
+}
+
+func buildEMFDocument(namespace string, dimensionNames []string, properties map[string]interface{}, metric emfMetricDirective, timestamp time.Time) ([]byte, error) {
+	doc := make(map[string]interface{}, len(properties)+1)
+	for k, v := range properties {
+		doc[k] = v
+	}
+	doc["_aws"] = emfMetadata{
+		Timestamp: timestamp.UnixNano() / int64(time.Millisecond),
+		CloudWatchMetrics: []emfMetricsDefinition{
+			{
+				Namespace:  namespace,
+				Dimensions: [][]string{dimensionNames},
+				Metrics:    []emfMetricDirective{metric},
+			},
+		},
+	}
+	return json.Marshal(doc)
+}
+
+// logEventBatch accumulates InputLogEvents for a single log stream until it is full enough
+// (by count or by size) to publish, mirroring the role MetricDatumBatch plays for datums.
+type logEventBatch struct {
+	Events    []*cloudwatchlogs.InputLogEvent
+	Size      int
+	BeginTime time.Time
+}
+
+func newLogEventBatch() *logEventBatch {
+	return &logEventBatch{
+		Events:    make([]*cloudwatchlogs.InputLogEvent, 0, maxLogEventsPerCall),
+		BeginTime: time.Now(),
+	}
+}
+
+func (b *logEventBatch) clear() {
+	b.Events = make([]*cloudwatchlogs.InputLogEvent, 0, maxLogEventsPerCall)
+	b.Size = 0
+	b.BeginTime = time.Now()
+}
+
+func (b *logEventBatch) isFull() bool {
+	return len(b.Events) >= maxLogEventsPerCall || b.Size >= maxLogEventBatchBytes
+}
+
+// logEventPayload is what gets handed off to the publisher for the EMF path, analogous to
+// cloudWatchPayload on the PutMetricData path.
+type logEventPayload struct {
+	Namespace string
+	Events    []*cloudwatchlogs.InputLogEvent
+}
+
+func (c *CloudWatch) getOrCreateLogEventBatch(namespace string) *logEventBatch {
+	if batch, ok := c.logEventBatches[namespace]; ok {
+		return batch
+	}
+	batch := newLogEventBatch()
+	c.logEventBatches[namespace] = batch
+	return batch
+}
+
+// pushEMFLogEvents builds the EMF documents for point and appends them to the appropriate
+// per-namespace batch, flushing any batch that becomes full in the process.
+func (c *CloudWatch) pushEMFLogEvents(point telegraf.Metric) {
+	docsByNamespace := c.BuildEMFLogs(point)
+	timestampMillis := aws.Int64(point.Time().UnixNano() / int64(time.Millisecond))
+	for namespace, docs := range docsByNamespace {
+		batch := c.getOrCreateLogEventBatch(namespace)
+		for _, doc := range docs {
+			event := &cloudwatchlogs.InputLogEvent{
+				Message:   aws.String(string(doc)),
+				Timestamp: timestampMillis,
+			}
+			eventSize := len(doc) + logEventOverheadBytes
+			if len(batch.Events) > 0 && batch.Size+eventSize > maxLogEventBatchBytes {
+				// flush first so the batch never grows past maxLogEventBatchBytes
+				c.datumBatchChan <- logEventPayload{Namespace: namespace, Events: batch.Events}
+				batch.clear()
+			}
+			batch.Events = append(batch.Events, event)
+			batch.Size += eventSize
+			if batch.isFull() {
+				c.datumBatchChan <- logEventPayload{Namespace: namespace, Events: batch.Events}
+				batch.clear()
+			}
+		}
+	}
+}
+
+// flushEMFLogEventBatches publishes any non-empty, non-full batch whose ForceFlushInterval
+// has elapsed, mirroring timeToPublish on the PutMetricData path.
+func (c *CloudWatch) flushEMFLogEventBatches() {
+	for namespace, batch := range c.logEventBatches {
+		if len(batch.Events) > 0 && time.Since(batch.BeginTime) >= c.ForceFlushInterval.Duration {
+			c.datumBatchChan <- logEventPayload{Namespace: namespace, Events: batch.Events}
+			batch.clear()
+		}
+	}
+}
+
+// ensureLogGroupAndStream creates c.LogGroupName/c.LogStreamName, tolerating either already
+// existing. PutLogEvents fails with ErrCodeResourceNotFoundException against a log group or
+// stream that doesn't exist yet, and CloudWatch Logs does not create either implicitly.
+func (c *CloudWatch) ensureLogGroupAndStream() error {
+	if _, err := c.logsSvc.CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(c.LogGroupName),
+	}); err != nil {
+		if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != cloudwatchlogs.ErrCodeResourceAlreadyExistsException {
+			return err
+		}
+	}
+	if _, err := c.logsSvc.CreateLogStream(&cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(c.LogGroupName),
+		LogStreamName: aws.String(c.LogStreamName),
+	}); err != nil {
+		if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != cloudwatchlogs.ErrCodeResourceAlreadyExistsException {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLogEventsToCloudWatch is the publisher callback for the EMF path: it ships a batch
+// of EMF-encoded InputLogEvents via PutLogEvents instead of PutMetricData, retrying
+// throttling/service errors the same way WriteToCloudWatch does for PutMetricData.
+func (c *CloudWatch) WriteLogEventsToCloudWatch(req interface{}) {
+	logPayload := req.(logEventPayload)
+	// PutLogEvents requires the batch to be in chronological order by Timestamp and rejects
+	// an unordered one with InvalidParameterException; events are appended to the batch in
+	// arrival order, which isn't guaranteed monotonic (e.g. interleaved high-res/standard
+	// points), so sort before every call.
+	sort.Slice(logPayload.Events, func(i, j int) bool {
+		return *logPayload.Events[i].Timestamp < *logPayload.Events[j].Timestamp
+	})
+	params := &cloudwatchlogs.PutLogEventsInput{
+		LogEvents:     logPayload.Events,
+		LogGroupName:  aws.String(c.LogGroupName),
+		LogStreamName: aws.String(c.LogStreamName),
+	}
+	if c.limiter != nil {
+		_ = c.limiter.Wait(context.Background())
+	}
+	if c.publishSem != nil {
+		c.publishSem <- struct{}{}
+		defer func() { <-c.publishSem }()
+	}
+
+	createdLogGroupStream := false
+	var err error
+	for i := 0; i < defaultRetryCount; i++ {
+		_, err = c.logsSvc.PutLogEvents(params)
+
+		if err != nil {
+			awsErr, ok := err.(awserr.Error)
+			if !ok {
+				log.Printf("E! Cannot cast PutLogEvents error %v into awserr.Error.", err)
+				c.backoffSleep()
+				continue
+			}
+			switch awsErr.Code() {
+			case cloudwatchlogs.ErrCodeResourceNotFoundException:
+				// log_group_name/log_stream_name must exist before PutLogEvents will accept
+				// events into them; create them lazily, once, then retry the same call.
+				if createdLogGroupStream {
+					log.Printf("E! cloudwatch: PutLogEvents still failing with %s after creating log_group_name/log_stream_name, giving up", awsErr.Code())
+					return
+				}
+				if createErr := c.ensureLogGroupAndStream(); createErr != nil {
+					log.Printf("E! cloudwatch: failed to create log_group_name %q / log_stream_name %q: %v", c.LogGroupName, c.LogStreamName, createErr)
+					return
+				}
+				createdLogGroupStream = true
+				continue
+			case cloudwatchlogs.ErrCodeServiceUnavailableException, cloudwatchlogs.ErrCodeThrottlingException:
+				log.Printf("W! cloudwatch putlogevents met issue: %s, message: %s",
+					awsErr.Code(),
+					awsErr.Message())
+				c.backoffSleep()
+				continue
+
+			default:
+				log.Printf("E! cloudwatch: code: %s, message: %s, original error: %+v", awsErr.Code(), awsErr.Message(), awsErr.OrigErr())
+				c.backoffSleep()
+			}
+		} else {
+			c.retries = 0
+		}
+		break
+	}
+	if err != nil {
+		log.Printf("E! WriteLogEventsToCloudWatch failure, namespace: %s, err: %v", logPayload.Namespace, err)
+	}
+}
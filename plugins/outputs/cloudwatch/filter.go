@@ -0,0 +1,127 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	filterActionDrop       = "drop"
+	filterActionKeep       = "keep"
+	filterActionReplace    = "replace"
+	filterActionLabelDrop  = "labeldrop"
+	filterActionLabelKeep  = "labelkeep"
+	filterSourceLabelsJoin = ";"
+)
+
+// MetricFilterConfig is one [[metric_filter]] block: a declarative rule to drop, keep, or
+// rewrite metrics/dimensions before they reach the aggregator, modeled on Prometheus's
+// relabel_config but operating over telegraf.Metric tags. Rules match against tag values (or
+// the metric name when source_labels is empty); field names are not matched or rewritten.
+type MetricFilterConfig struct {
+	Action       string   `toml:"action"`
+	SourceLabels []string `toml:"source_labels"`
+	Regex        string   `toml:"regex"`
+	TargetLabel  string   `toml:"target_label"`
+	Replacement  string   `toml:"replacement"`
+}
+
+// compiledMetricFilter is a MetricFilterConfig with its regex compiled once at Connect()
+// time instead of on every metric.
+type compiledMetricFilter struct {
+	action       string
+	sourceLabels []string
+	regex        *regexp.Regexp
+	targetLabel  string
+	replacement  string
+}
+
+func compileMetricFilters(configs []MetricFilterConfig) ([]compiledMetricFilter, error) {
+	compiled := make([]compiledMetricFilter, 0, len(configs))
+	for _, cfg := range configs {
+		switch cfg.Action {
+		case filterActionDrop, filterActionKeep, filterActionReplace, filterActionLabelDrop, filterActionLabelKeep:
+		case "":
+			return nil, fmt.Errorf("cloudwatch: metric_filter is missing required field \"action\"")
+		default:
+			return nil, fmt.Errorf("cloudwatch: metric_filter has unknown action %q", cfg.Action)
+		}
+
+		regex := cfg.Regex
+		if regex == "" {
+			regex = ".*"
+		}
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, fmt.Errorf("cloudwatch: metric_filter has invalid regex %q: %w", cfg.Regex, err)
+		}
+
+		compiled = append(compiled, compiledMetricFilter{
+			action:       cfg.Action,
+			sourceLabels: cfg.SourceLabels,
+			regex:        re,
+			targetLabel:  cfg.TargetLabel,
+			replacement:  cfg.Replacement,
+		})
+	}
+	return compiled, nil
+}
+
+// sourceValue builds the string a filter's regex is matched against: the values of
+// sourceLabels, in order, joined by ";", following the same convention Prometheus's
+// relabel_config uses.
+func sourceValue(m telegraf.Metric, sourceLabels []string) string {
+	if len(sourceLabels) == 0 {
+		return m.Name()
+	}
+	values := make([]string, len(sourceLabels))
+	for i, label := range sourceLabels {
+		values[i] = m.Tags()[label]
+	}
+	return strings.Join(values, filterSourceLabelsJoin)
+}
+
+// filterAndRelabel applies every configured metric_filter rule, in order, to m. It returns
+// nil when a "drop" or failed "keep" rule eliminates the metric entirely, and otherwise
+// returns m with "replace"/"labeldrop"/"labelkeep" rules applied in place.
+func (c *CloudWatch) filterAndRelabel(m telegraf.Metric) telegraf.Metric {
+	for _, f := range c.compiledFilters {
+		switch f.action {
+		case filterActionDrop:
+			if f.regex.MatchString(sourceValue(m, f.sourceLabels)) {
+				return nil
+			}
+		case filterActionKeep:
+			if !f.regex.MatchString(sourceValue(m, f.sourceLabels)) {
+				return nil
+			}
+		case filterActionReplace:
+			if f.targetLabel == "" {
+				continue
+			}
+			value := sourceValue(m, f.sourceLabels)
+			if match := f.regex.FindStringSubmatchIndex(value); match != nil {
+				m.AddTag(f.targetLabel, string(f.regex.ExpandString(nil, f.replacement, value, match)))
+			}
+		case filterActionLabelDrop:
+			for tag := range m.Tags() {
+				if f.regex.MatchString(tag) {
+					m.RemoveTag(tag)
+				}
+			}
+		case filterActionLabelKeep:
+			for tag := range m.Tags() {
+				if !f.regex.MatchString(tag) {
+					m.RemoveTag(tag)
+				}
+			}
+		}
+	}
+	return m
+}
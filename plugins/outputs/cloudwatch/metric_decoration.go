@@ -0,0 +1,92 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import "fmt"
+
+// MetricDecorationConfig is one [[outputs.cloudwatch.metric_decoration]] block: a per-metric
+// override, keyed by category (the point name) and the field name within it.
+type MetricDecorationConfig struct {
+	Category string `toml:"category"`
+	Name     string `toml:"name"`
+	Rename   string `toml:"rename"`
+	Unit     string `toml:"unit"`
+
+	// StatisticOnly and StatisticType apply to this category/name only; see the CloudWatch
+	// struct fields WriteStatisticsOnly/StatisticType. StatisticOnly = true enables
+	// statistic-only output; false leaves WriteStatisticsOnly in effect. A non-empty
+	// StatisticType replaces the plugin-wide StatisticType.
+	StatisticOnly bool   `toml:"statistic_only"`
+	StatisticType string `toml:"statistic_type"`
+
+	// Namespace overrides which CloudWatch namespace this category/name is published under.
+	// It must be either the plugin's top-level "namespace" or one of "namespaces"; see
+	// (*CloudWatch).resolveNamespace.
+	Namespace string `toml:"namespace"`
+}
+
+type metricDecoration struct {
+	rename        string
+	unit          string
+	statisticOnly bool
+	statisticType string
+	namespace     string
+}
+
+// MetricDecorations indexes metric_decoration overrides by category/name so per-field
+// lookups in BuildMetricDatum stay O(1) instead of scanning MetricConfigs on every point.
+type MetricDecorations struct {
+	mappings map[string]metricDecoration
+}
+
+// NewMetricDecorations compiles configs into a MetricDecorations ready for lookups.
+func NewMetricDecorations(configs []MetricDecorationConfig) (*MetricDecorations, error) {
+	d := &MetricDecorations{mappings: make(map[string]metricDecoration, len(configs))}
+	for _, cfg := range configs {
+		if cfg.Category == "" || cfg.Name == "" {
+			return nil, fmt.Errorf("cloudwatch: metric_decoration requires both \"category\" and \"name\"")
+		}
+		d.mappings[decorationKey(cfg.Category, cfg.Name)] = metricDecoration{
+			rename:        cfg.Rename,
+			unit:          cfg.Unit,
+			statisticOnly: cfg.StatisticOnly,
+			statisticType: cfg.StatisticType,
+			namespace:     cfg.Namespace,
+		}
+	}
+	return d, nil
+}
+
+func decorationKey(category, name string) string {
+	return category + "_" + name
+}
+
+// getRename returns the metric_decoration "rename" override for category/name, or "" if none
+// is configured.
+func (d *MetricDecorations) getRename(category, name string) string {
+	return d.mappings[decorationKey(category, name)].rename
+}
+
+// getUnit returns the metric_decoration "unit" override for category/name, or "" if none is
+// configured.
+func (d *MetricDecorations) getUnit(category, name string) string {
+	return d.mappings[decorationKey(category, name)].unit
+}
+
+// getStatisticOnly returns the metric_decoration "statistic_only" override for category/name.
+func (d *MetricDecorations) getStatisticOnly(category, name string) bool {
+	return d.mappings[decorationKey(category, name)].statisticOnly
+}
+
+// getStatisticType returns the metric_decoration "statistic_type" override for category/name,
+// or "" if none is configured.
+func (d *MetricDecorations) getStatisticType(category, name string) string {
+	return d.mappings[decorationKey(category, name)].statisticType
+}
+
+// getNamespace returns the metric_decoration "namespace" override for category/name, or "" if
+// none is configured.
+func (d *MetricDecorations) getNamespace(category, name string) string {
+	return d.mappings[decorationKey(category, name)].namespace
+}
@@ -0,0 +1,302 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	defaultSpoolMaxBytes = 100 * 1024 * 1024 // 100MB
+	spoolSegmentMaxBytes = 8 * 1024 * 1024   // roll to a new segment file after this many bytes
+	spoolFilePrefix      = "cwagent-spool-"
+	spoolFileSuffix      = ".seg"
+)
+
+// walRecord is the on-disk unit a spool segment stores: one batch bound for a single
+// namespace, gob-encoded, identical in shape to cloudWatchPayload minus its WAL backing.
+type walRecord struct {
+	Namespace string
+	Datums    []*cloudwatch.MetricDatum
+}
+
+// walSegment is one spool file. Segments roll at spoolSegmentMaxBytes and are deleted once
+// they are both rolled (no longer the active segment) and every record in them has been
+// acked, or earlier, acked or not, when evictOldestLocked evicts them to stay under maxBytes.
+type walSegment struct {
+	path    string
+	file    *os.File
+	size    int64
+	records int
+	acked   int
+	rolled  bool
+	// evicted is set once evictOldestLocked has already charged this segment's outstanding
+	// (unacked) records against depth, so a later Ack for one of those records (the batch
+	// was already in flight when the segment was evicted) doesn't double-decrement it.
+	evicted bool
+}
+
+// walRef identifies which segment a published batch came from (each Ack counts one record),
+// so WriteToCloudWatch can mark it durable-complete once PutMetricData succeeds.
+type walRef struct {
+	segment *walSegment
+}
+
+// spool is the write-ahead log used to survive agent restarts and sustained CloudWatch
+// outages without silently dropping buffered metric batches. pushMetricDatum appends a
+// batch's record before it's handed to the publisher; WriteToCloudWatch acks (and
+// eventually deletes) it only once PutMetricData actually succeeds. Only the PutMetricData
+// path (EmitMode != "emf") ever constructs a spool, since replay always publishes
+// cloudWatchPayload records; Connect rejects pairing it with EmitMode "emf".
+type spool struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	used     int64
+	segments []*walSegment
+	active   *walSegment
+	// depth is tagged by spool_directory so multiple CloudWatch output instances, each with
+	// their own spool, don't all report through the same selfstat series.
+	depth selfstat.Stat
+}
+
+// openSpool prepares dir to receive spool segments. It does not replay existing segments;
+// callers should do that explicitly (see (*CloudWatch).replaySpool) once they're ready to
+// re-publish what they find.
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cloudwatch: failed to create spool directory %s: %w", dir, err)
+	}
+	depth := selfstat.Register("cloudwatch", "spool_depth", map[string]string{"spool_directory": dir})
+	return &spool{dir: dir, maxBytes: maxBytes, depth: depth}, nil
+}
+
+// Append writes rec to the active segment (rolling to a new one if it would overflow
+// spoolSegmentMaxBytes), evicting the oldest segments if the spool has grown past maxBytes,
+// and returns a reference the caller must Ack once rec has been published. Writes are not
+// fsync'd, so this protects against the agent process crashing or being restarted, not
+// against the host losing power before the OS flushes its page cache.
+func (s *spool) Append(rec walRecord) (*walRef, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rec); err != nil {
+		return nil, fmt.Errorf("cloudwatch: failed to encode spool record: %w", err)
+	}
+	payload := buf.Bytes()
+	checksum := crc32.ChecksumIEEE(payload)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil || s.active.size+int64(len(payload))+8 > spoolSegmentMaxBytes {
+		if err := s.rollActiveLocked(); err != nil {
+			return nil, err
+		}
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], checksum)
+	if _, err := s.active.file.Write(header[:]); err != nil {
+		return nil, fmt.Errorf("cloudwatch: failed to write spool record header: %w", err)
+	}
+	if _, err := s.active.file.Write(payload); err != nil {
+		return nil, fmt.Errorf("cloudwatch: failed to write spool record: %w", err)
+	}
+
+	written := int64(len(header)) + int64(len(payload))
+	s.active.size += written
+	s.active.records++
+	s.used += written
+	s.depth.Incr(1)
+
+	s.evictOldestLocked()
+
+	return &walRef{segment: s.active}, nil
+}
+
+// Ack marks one record of ref's segment as durably published. Once every record in a
+// rolled segment has been acked, the segment file is deleted.
+func (s *spool) Ack(ref *walRef) {
+	if ref == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref.segment.acked++
+	// If evictOldestLocked already charged this segment's then-outstanding records against
+	// depth, don't decrement it again for a batch that was simply in flight at the time.
+	if !ref.segment.evicted {
+		s.depth.Incr(-1)
+	}
+	s.maybeDeleteLocked(ref.segment)
+}
+
+func (s *spool) maybeDeleteLocked(seg *walSegment) {
+	if seg.evicted || !seg.rolled || seg.acked < seg.records {
+		return
+	}
+	if seg.file != nil {
+		seg.file.Close()
+	}
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("E! cloudwatch: failed to remove spent spool segment %s: %v", seg.path, err)
+	}
+	s.used -= seg.size
+	for i, s2 := range s.segments {
+		if s2 == seg {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+}
+
+// evictOldestLocked drops the oldest rolled segments, whether or not they've been fully
+// acked, once the spool exceeds its configured size cap. This trades durability for bounded
+// disk usage, same tradeoff the in-memory channels it backs already make.
+func (s *spool) evictOldestLocked() {
+	for s.used > s.maxBytes && len(s.segments) > 0 {
+		oldest := s.segments[0]
+		if oldest == s.active {
+			break
+		}
+		if oldest.file != nil {
+			oldest.file.Close()
+		}
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("E! cloudwatch: failed to evict oldest spool segment %s: %v", oldest.path, err)
+		}
+		s.used -= oldest.size
+		s.depth.Incr(-int64(oldest.records - oldest.acked))
+		oldest.evicted = true
+		s.segments = s.segments[1:]
+	}
+}
+
+func (s *spool) rollActiveLocked() error {
+	if s.active != nil {
+		s.active.rolled = true
+		s.maybeDeleteLocked(s.active)
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("%s%d%s", spoolFilePrefix, time.Now().UnixNano(), spoolFileSuffix))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("cloudwatch: failed to create spool segment %s: %w", path, err)
+	}
+	seg := &walSegment{path: path, file: f}
+	s.active = seg
+	s.segments = append(s.segments, seg)
+	return nil
+}
+
+// readSegment parses every valid, CRC-checked record out of an existing spool segment
+// file, stopping at the first short/corrupt record (the expected shape of a record
+// truncated mid-write by a crash).
+func readSegment(path string) ([]walRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []walRecord
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(f, header[:]); err != nil {
+			break
+		}
+		length := binary.BigEndian.Uint32(header[0:4])
+		checksum := binary.BigEndian.Uint32(header[4:8])
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(f, payload); err != nil {
+			log.Printf("W! cloudwatch: spool segment %s ends with a truncated record, stopping replay there", path)
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != checksum {
+			log.Printf("W! cloudwatch: spool segment %s has a corrupt record, stopping replay there", path)
+			break
+		}
+		var rec walRecord
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&rec); err != nil {
+			log.Printf("W! cloudwatch: spool segment %s has an undecodable record, stopping replay there", path)
+			break
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// replaySpool re-publishes every record left over in c.SpoolDirectory from a previous run,
+// ahead of starting the ingest goroutines, so a restart during an outage doesn't lose what
+// was already durably buffered.
+func (c *CloudWatch) replaySpool() {
+	entries, err := os.ReadDir(c.spool.dir)
+	if err != nil {
+		log.Printf("E! cloudwatch: failed to list spool directory %s for replay: %v", c.spool.dir, err)
+		return
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && len(entry.Name()) > len(spoolFilePrefix)+len(spoolFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names) // segment names are timestamp-ordered
+
+	replayed := 0
+	for _, name := range names {
+		path := filepath.Join(c.spool.dir, name)
+		records, err := readSegment(path)
+		if err != nil {
+			log.Printf("E! cloudwatch: failed to read spool segment %s, skipping: %v", path, err)
+			continue
+		}
+		if len(records) == 0 {
+			os.Remove(path)
+			continue
+		}
+
+		var size int64
+		if info, statErr := os.Stat(path); statErr == nil {
+			size = info.Size()
+		} else {
+			log.Printf("W! cloudwatch: failed to stat spool segment %s, spool_max_bytes accounting will undercount it: %v", path, statErr)
+		}
+
+		// Register the segment (and account its bytes/depth) under s.mu before publishing
+		// any of its records: publisher worker goroutines are already running by the time
+		// replaySpool runs, and a worker's WriteToCloudWatch -> spool.Ack -> maybeDeleteLocked
+		// mutates s.segments under s.mu concurrently with this loop appending to it.
+		c.spool.mu.Lock()
+		seg := &walSegment{path: path, records: len(records), rolled: true, size: size}
+		c.spool.segments = append(c.spool.segments, seg)
+		c.spool.used += size
+		for range records {
+			c.spool.depth.Incr(1)
+		}
+		c.spool.mu.Unlock()
+
+		for _, rec := range records {
+			c.publisher.Publish(cloudWatchPayload{Namespace: rec.Namespace, Datums: rec.Datums, walRef: &walRef{segment: seg}})
+			replayed++
+		}
+	}
+	if replayed > 0 {
+		log.Printf("I! cloudwatch: replayed %d metric batch(es) from spool directory %s", replayed, c.spool.dir)
+	}
+}